@@ -0,0 +1,194 @@
+package main
+
+import (
+  "encoding/json"
+  "net/http"
+  "net/http/httptest"
+  "os"
+  "path/filepath"
+  "testing"
+
+  "github.com/enriquebris/goconcurrentqueue"
+)
+
+func newTestAdminServer(t *testing.T, registry *Registry, configPath string) (*AdminServer, *httptest.Server) {
+  t.Helper()
+  a := NewAdminServer(registry, goconcurrentqueue.NewFIFO(), configPath)
+  srv := httptest.NewServer(a.mux())
+  t.Cleanup(srv.Close)
+  return a, srv
+}
+
+func writeTestConfig(t *testing.T, yaml string) string {
+  t.Helper()
+  path := filepath.Join(t.TempDir(), "config.yaml")
+  if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+    t.Fatal(err)
+  }
+  return path
+}
+
+func TestAdminHandleRunUnknownCheck404s(t *testing.T) {
+  registry := newTestRegistry()
+  _, srv := newTestAdminServer(t, registry, "")
+
+  resp, err := http.Post(srv.URL+"/checks/ghost/run", "application/json", nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer resp.Body.Close()
+  if resp.StatusCode != http.StatusNotFound {
+    t.Errorf("expected 404 for an unknown check, got %d", resp.StatusCode)
+  }
+}
+
+func TestAdminHandleRunExecutesAndReturnsResult(t *testing.T) {
+  registry := newTestRegistry()
+  registry.Add(tcpCheck("a", "127.0.0.1", 1)) // nothing listens on port 1: deterministic down
+  _, srv := newTestAdminServer(t, registry, "")
+
+  resp, err := http.Post(srv.URL+"/checks/a/run", "application/json", nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer resp.Body.Close()
+  if resp.StatusCode != http.StatusOK {
+    t.Fatalf("expected 200, got %d", resp.StatusCode)
+  }
+
+  var cr CheckResult
+  if err := json.NewDecoder(resp.Body).Decode(&cr); err != nil {
+    t.Fatal(err)
+  }
+  if cr.OriginalCheck.Name != "a" {
+    t.Errorf("expected the result for check a, got %+v", cr)
+  }
+}
+
+func TestAdminHandleListReturnsChecksAndStats(t *testing.T) {
+  registry := newTestRegistry()
+  registry.Add(tcpCheck("a", "a.example.com", 80))
+  registry.RecordResult(CheckResult{Domain: "example.com", Result: true})
+  _, srv := newTestAdminServer(t, registry, "")
+
+  resp, err := http.Get(srv.URL + "/checks")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer resp.Body.Close()
+
+  var out struct {
+    Checks []Check `json:"checks"`
+    Stats map[string]DomainStats `json:"stats"`
+  }
+  if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+    t.Fatal(err)
+  }
+  if len(out.Checks) != 1 || out.Checks[0].Name != "a" {
+    t.Errorf("expected check a listed, got %+v", out.Checks)
+  }
+  if out.Stats["example.com"].Total != 1 {
+    t.Errorf("expected example.com stats to be included, got %+v", out.Stats)
+  }
+}
+
+func TestAdminHandleReloadAddsRemovesAndPreservesStats(t *testing.T) {
+  registry := newTestRegistry()
+  registry.Add(tcpCheck("a", "a.example.com", 80))
+  registry.Add(tcpCheck("b", "b.example.com", 80))
+  registry.RecordResult(CheckResult{Domain: "example.com", Result: true})
+
+  configPath := writeTestConfig(t, `
+checks:
+  - name: a
+    type: tcp
+    host: a.example.com
+    port: 80
+  - name: c
+    type: tcp
+    host: c.example.com
+    port: 80
+`)
+  _, srv := newTestAdminServer(t, registry, configPath)
+
+  resp, err := http.Post(srv.URL+"/reload", "application/json", nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer resp.Body.Close()
+  if resp.StatusCode != http.StatusNoContent {
+    t.Fatalf("expected 204 from a successful reload, got %d", resp.StatusCode)
+  }
+
+  if _, ok := registry.Get("a"); !ok {
+    t.Error("check a from the reloaded config should still be present")
+  }
+  if _, ok := registry.Get("b"); ok {
+    t.Error("check b dropped from the reloaded config should be removed")
+  }
+  if _, ok := registry.Get("c"); !ok {
+    t.Error("check c newly added by the reloaded config should be present")
+  }
+  if stats := registry.StatsSnapshot()["example.com"]; stats.Total != 1 {
+    t.Errorf("reload must not reset accumulated stats for an unchanged check, got %+v", stats)
+  }
+}
+
+func TestAdminHandleReloadReturnsBadRequestOnMalformedConfig(t *testing.T) {
+  registry := newTestRegistry()
+  registry.Add(tcpCheck("a", "a.example.com", 80))
+  configPath := writeTestConfig(t, "not: [valid")
+  _, srv := newTestAdminServer(t, registry, configPath)
+
+  resp, err := http.Post(srv.URL+"/reload", "application/json", nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer resp.Body.Close()
+  if resp.StatusCode != http.StatusBadRequest {
+    t.Errorf("expected 400 for a malformed config, got %d", resp.StatusCode)
+  }
+
+  var body struct {
+    Error string `json:"error"`
+  }
+  if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+    t.Fatal(err)
+  }
+  if body.Error == "" {
+    t.Error("expected a structured JSON error body")
+  }
+  if _, ok := registry.Get("a"); !ok {
+    t.Error("a failed reload must leave the running registry untouched")
+  }
+}
+
+func TestAdminHandlePauseAndResume(t *testing.T) {
+  registry := newTestRegistry()
+  registry.Add(tcpCheck("a", "a.example.com", 80))
+  _, srv := newTestAdminServer(t, registry, "")
+
+  resp, err := http.Post(srv.URL+"/pause", "application/json", nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != http.StatusNoContent {
+    t.Fatalf("expected 204 from /pause, got %d", resp.StatusCode)
+  }
+  if !registry.paused {
+    t.Error("expected the registry to be paused after /pause")
+  }
+
+  resp, err = http.Post(srv.URL+"/resume", "application/json", nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != http.StatusNoContent {
+    t.Fatalf("expected 204 from /resume, got %d", resp.StatusCode)
+  }
+  if registry.paused {
+    t.Error("expected the registry to be resumed after /resume")
+  }
+}
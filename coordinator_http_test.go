@@ -0,0 +1,158 @@
+package main
+
+import (
+  "bytes"
+  "encoding/json"
+  "net/http"
+  "net/http/httptest"
+  "testing"
+  "time"
+)
+
+func TestCoordinatorLongPollUnblocksOnVersionBump(t *testing.T) {
+  c := NewCoordinator(newTestRegistry(), 1)
+  srv := httptest.NewServer(c.mux())
+  defer srv.Close()
+
+  type pollResp struct {
+    Version int `json:"version"`
+    Checks []Check `json:"checks"`
+  }
+
+  done := make(chan pollResp, 1)
+  go func() {
+    resp, err := http.Get(srv.URL + "/workers/w1/checks?since=1")
+    if err != nil {
+      t.Error(err)
+      return
+    }
+    defer resp.Body.Close()
+    var out pollResp
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+      t.Error(err)
+      return
+    }
+    done <- out
+  }()
+
+  // Give the long-poll a moment to block on the initial version before
+  // bumping it, otherwise the race is indistinguishable from "didn't block".
+  time.Sleep(50 * time.Millisecond)
+  c.bumpChecksVersion()
+
+  select {
+  case out := <-done:
+    if out.Version != 2 {
+      t.Errorf("expected long-poll to unblock with the bumped version 2, got %d", out.Version)
+    }
+  case <-time.After(5 * time.Second):
+    t.Fatal("long-poll did not unblock after bumpChecksVersion")
+  }
+}
+
+func TestCoordinatorLongPollReturnsRegisteredChecks(t *testing.T) {
+  registry := newTestRegistry()
+  registry.Add(tcpCheck("a", "a.example.com", 80))
+  c := NewCoordinator(registry, 1)
+  srv := httptest.NewServer(c.mux())
+  defer srv.Close()
+
+  resp, err := http.Get(srv.URL + "/workers/w1/checks?since=0")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer resp.Body.Close()
+
+  var out struct {
+    Version int `json:"version"`
+    Checks []Check `json:"checks"`
+  }
+  if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+    t.Fatal(err)
+  }
+  if len(out.Checks) != 1 || out.Checks[0].Name != "a" {
+    t.Errorf("expected the registry's single check back, got %+v", out.Checks)
+  }
+}
+
+func TestCoordinatorHeartbeat404sForUnregisteredWorker(t *testing.T) {
+  c := NewCoordinator(newTestRegistry(), 1)
+  srv := httptest.NewServer(c.mux())
+  defer srv.Close()
+
+  resp, err := http.Post(srv.URL+"/workers/ghost/heartbeat", "application/json", nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer resp.Body.Close()
+  if resp.StatusCode != http.StatusNotFound {
+    t.Errorf("expected 404 for an unregistered worker, got %d", resp.StatusCode)
+  }
+}
+
+func TestCoordinatorHeartbeat204sAfterRegister(t *testing.T) {
+  c := NewCoordinator(newTestRegistry(), 1)
+  srv := httptest.NewServer(c.mux())
+  defer srv.Close()
+
+  body, _ := json.Marshal(struct {
+    WorkerID string `json:"worker_id"`
+    Vantage string `json:"vantage"`
+  }{WorkerID: "w1", Vantage: "us-east"})
+  regResp, err := http.Post(srv.URL+"/workers/register", "application/json", bytes.NewReader(body))
+  if err != nil {
+    t.Fatal(err)
+  }
+  regResp.Body.Close()
+
+  hbResp, err := http.Post(srv.URL+"/workers/w1/heartbeat", "application/json", nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer hbResp.Body.Close()
+  if hbResp.StatusCode != http.StatusNoContent {
+    t.Errorf("expected 204 for a registered worker's heartbeat, got %d", hbResp.StatusCode)
+  }
+}
+
+func TestCoordinatorHandleReportIngestsThroughHTTP(t *testing.T) {
+  c := NewCoordinator(newTestRegistry(), 1)
+  srv := httptest.NewServer(c.mux())
+  defer srv.Close()
+
+  report := VantageReport{
+    Vantage: "us-east",
+    Results: []CheckResult{
+      {OriginalCheck: Check{Name: "a"}, Domain: "example.com", Result: true},
+    },
+  }
+  body, _ := json.Marshal(report)
+  resp, err := http.Post(srv.URL+"/workers/w1/results", "application/json", bytes.NewReader(body))
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer resp.Body.Close()
+  if resp.StatusCode != http.StatusNoContent {
+    t.Fatalf("expected 204 from handleReport, got %d", resp.StatusCode)
+  }
+
+  stats := c.vantageStats["example.com"][aggregatedVantage]
+  if stats.Total != 1 || stats.Up != 1 {
+    t.Errorf("handleReport should have ingested the report into aggregated stats, got %+v", stats)
+  }
+}
+
+func TestCoordinatorHandleReportRejectsBadJSON(t *testing.T) {
+  c := NewCoordinator(newTestRegistry(), 1)
+  srv := httptest.NewServer(c.mux())
+  defer srv.Close()
+
+  resp, err := http.Post(srv.URL+"/workers/w1/results", "application/json", bytes.NewReader([]byte("not json")))
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer resp.Body.Close()
+  if resp.StatusCode != http.StatusBadRequest {
+    t.Errorf("expected 400 for a malformed report body, got %d", resp.StatusCode)
+  }
+}
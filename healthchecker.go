@@ -1,36 +1,195 @@
 package main
 
 import (
+  "bytes"
   "context"
+  "encoding/json"
+  "flag"
   "fmt"
   "log/slog"
+  "net"
   "net/http"
   "net/http/httptrace"
   "net/url"
   "os"
+  "os/exec"
+  "os/signal"
+  "reflect"
+  "strconv"
   "strings"
+  "sync"
+  "syscall"
   "time"
   "crypto/tls"
+  "crypto/x509"
   "gopkg.in/yaml.v3"
   "github.com/enriquebris/goconcurrentqueue"
+  "github.com/prometheus/client_golang/prometheus"
+  "github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type Check struct {
     Name string `yaml:"name"`
     Domain string
+    Type string `yaml:"type"`
     URL string `yaml:"url"`
     Method string `yaml:"method"`
     Headers map[string]string `yaml:"headers"`
     Body string `yaml:"body"`
     IP string
+    Interval Duration `yaml:"interval"`
+    Timeout Duration `yaml:"timeout"`
+    Attempts int `yaml:"attempts"`
+    RetryBackoff Duration `yaml:"retry_backoff"`
+
+    // Host/Port are shared by the tcp, tls, dns, and icmp probers.
+    Host string `yaml:"host"`
+    Port int `yaml:"port"`
+
+    // dns
+    RecordType string `yaml:"record_type"`
+    ExpectedAnswer string `yaml:"expected_answer"`
+
+    // tls
+    CertExpiryWindow Duration `yaml:"cert_expiry_window"`
+
+    // icmp
+    RTTThreshold Duration `yaml:"rtt_threshold"`
+
+    // exec
+    Script string `yaml:"script"`
+    Args []string `yaml:"args"`
+}
+
+// Duration wraps time.Duration so checks can express timeout/interval/backoff
+// fields as YAML strings like "500ms" or "15s".
+type Duration struct {
+    time.Duration
+}
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+    var raw string
+    if err := value.Decode(&raw); err != nil {
+        return err
+    }
+    parsed, err := time.ParseDuration(raw)
+    if err != nil {
+        return err
+    }
+    d.Duration = parsed
+    return nil
+}
+
+const (
+    defaultTimeout          = 500 * time.Millisecond
+    defaultAttempts         = 1
+    defaultRetryBackoff     = 100 * time.Millisecond
+    defaultInterval         = 15 * time.Second
+    maxRetryBackoff         = 30 * time.Second
+    defaultCheckType        = "http"
+    defaultTLSPort          = 443
+    defaultCertExpiryWindow = 14 * 24 * time.Hour
+    defaultRTTThreshold     = 200 * time.Millisecond
+    defaultMetricsListen    = ":9090"
+    defaultAdminListen      = ":9091"
+    defaultCoordinatorListen = ":9092"
+
+    workerHeartbeatInterval = 10 * time.Second
+    workerHeartbeatTimeout  = 3 * workerHeartbeatInterval
+    workerLongPollTimeout   = 30 * time.Second
+    workerLongPollInterval  = 500 * time.Millisecond
+
+    defaultWorkerPoolSize = 4
+    // sendQueueCapacity bounds how many enqueued-but-not-yet-run checks can
+    // queue up; once full, further ticks for checks already pending are
+    // coalesced and ticks for other checks are dropped-and-logged rather
+    // than growing the queue without bound.
+    sendQueueCapacity = 4096
+)
+
+// Config is the top-level shape of the YAML config file: the list of
+// checks to run plus settings for the subsystems that serve them.
+type Config struct {
+    Checks []Check `yaml:"checks"`
+    Metrics MetricsConfig `yaml:"metrics"`
+    Admin AdminConfig `yaml:"admin"`
+}
+
+type MetricsConfig struct {
+    Listen string `yaml:"listen"`
+}
+
+type AdminConfig struct {
+    Listen string `yaml:"listen"`
+}
+
+// ProbeStats carries the per-phase timing and metadata a Prober gathered
+// while executing a single attempt.
+type ProbeStats struct {
+    DNS time.Duration
+    Connect time.Duration
+    TLSHandshake time.Duration
+    TTFB time.Duration
+    Total time.Duration
+    StatusCode int
+    CertExpiry time.Time
+    // RTT is the ping-reported round-trip time for icmp checks. It is
+    // reported alongside, not instead of, Total so that
+    // healthcheck_duration_seconds{phase="total"} stays comparable across
+    // every prober type.
+    RTT time.Duration
+}
+
+// Prober is the interface every check type implements in order to be
+// dispatched by runChecks. Target returns the string groupIntoDomains
+// should bucket this check under (a URL, a host:port, or a bare hostname).
+type Prober interface {
+    Probe(ctx context.Context) (bool, ProbeStats, error)
+    Target() string
+}
+
+// proberFactories is the registry of check types, keyed by the `type:`
+// YAML field. Add an entry here when introducing a new check type.
+var proberFactories = map[string]func(Check) (Prober, error){
+    "http": newHTTPProber,
+    "tcp": newTCPProber,
+    "tls": newTLSProber,
+    "dns": newDNSProber,
+    "icmp": newICMPProber,
+    "exec": newExecProber,
+}
+
+func buildProber(check Check) (Prober, error) {
+    factory, ok := proberFactories[check.Type]
+    if !ok {
+        return nil, fmt.Errorf("unknown check type %q for check %s", check.Type, check.Name)
+    }
+    return factory(check)
 }
 
 type CheckResult struct {
     OriginalCheck Check
     Domain string
     Result bool
+    Stats ProbeStats
 }
 
+// DomainStats accumulates Up/Total counts for a single domain. In the
+// single-host (coordinator-only) path, Registry.RecordResult increments it
+// once per completed check execution, so Total tracks executions 1:1.
+//
+// Under the coordinator/worker vantage-point model (chunk0-5), the
+// coordinator instead keeps one DomainStats per (domain, vantage) plus an
+// "aggregated" entry (see Coordinator.vantageStats): Coordinator.Ingest
+// increments the aggregated entry once per incoming VantageReport, i.e.
+// per individual vantage point's result, not once per synchronized round
+// across all vantage points. Workers long-poll and report independently
+// on their own schedules, so there is no round boundary the coordinator
+// could wait on without blocking aggregation on the slowest or a
+// disconnected vantage point; "aggregated availability" therefore means
+// "fraction of all vantage reports, across all vantage points, for which
+// a quorum was up at the time of that report" rather than "fraction of
+// rounds where a quorum was up".
 type DomainStats struct {
     DomainName string
     Up int
@@ -38,175 +197,1379 @@ type DomainStats struct {
 }
 
 func main() {
+    role := flag.String("role", "coordinator", "run as coordinator or worker")
+    configPath := flag.String("config", "", "path to the checks config file (coordinator only)")
+    debugFlag := flag.Bool("debug", false, "enable debug logging")
+    coordinatorListen := flag.String("coordinator-listen", defaultCoordinatorListen, "address the coordinator listens on for workers")
+    coordinatorAddr := flag.String("coordinator-addr", "", "coordinator address to connect to (worker only)")
+    vantagePoint := flag.String("vantage-point", "", "label identifying this worker's region/host (worker only)")
+    quorum := flag.Int("quorum", 1, "vantage points that must report a check up for it to count as up (coordinator only)")
+    tlsCert := flag.String("tls-cert", "", "TLS certificate for mutual auth between coordinator and workers")
+    tlsKey := flag.String("tls-key", "", "TLS private key for mutual auth between coordinator and workers")
+    tlsCA := flag.String("tls-ca", "", "CA bundle used to verify the coordinator/worker peer certificate")
+    workerPoolSize := flag.Int("workers", defaultWorkerPoolSize, "number of concurrent check execution goroutines")
+    flag.Parse()
 
     var programLevel = new(slog.LevelVar)
     h := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: programLevel})
     slog.SetDefault(slog.New(h))
-    if len(os.Args) > 2 && os.Args[2] == "debug" {
+    if *debugFlag {
         programLevel.Set(slog.LevelDebug)
     }
 
-    //Load Checks Config
-    var checks []Check = loadConfig(os.Args[1])
-    
-    var domainStats map[string]DomainStats = groupIntoDomains(checks)
-    
-    interval := time.Duration(15)*time.Second
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    defer stop()
+
+    switch *role {
+    case "worker":
+        tlsConfig, err := loadMutualTLSConfig(*tlsCert, *tlsKey, *tlsCA, false)
+        if err != nil {
+            slog.Error(fmt.Sprintf("Could not load TLS config: %v", err))
+            os.Exit(1)
+        }
+        runWorker(ctx, *coordinatorAddr, *vantagePoint, *workerPoolSize, tlsConfig)
+    case "coordinator":
+        if *configPath == "" {
+            slog.Error("coordinator role requires --config")
+            os.Exit(1)
+        }
+        tlsConfig, err := loadMutualTLSConfig(*tlsCert, *tlsKey, *tlsCA, true)
+        if err != nil {
+            slog.Error(fmt.Sprintf("Could not load TLS config: %v", err))
+            os.Exit(1)
+        }
+        runCoordinator(ctx, *configPath, *quorum, *coordinatorListen, *workerPoolSize, tlsConfig)
+    default:
+        slog.Error(fmt.Sprintf("unknown role %q (expected coordinator or worker)", *role))
+        os.Exit(1)
+    }
+}
+
+// runCoordinator loads the checks config, starts the metrics/admin/worker
+// facing servers, and runs the checks locally (as the "local" vantage
+// point) alongside whatever remote workers connect. It blocks until ctx
+// is cancelled (SIGINT/SIGTERM).
+func runCoordinator(ctx context.Context, configPath string, quorum int, listen string, poolSize int, tlsConfig *tls.Config) {
+    cfg, err := loadConfig(configPath)
+    if err != nil {
+        slog.Error(fmt.Sprintf("Could not load config: %v", err))
+        os.Exit(1)
+    }
 
-    sendQueue := goconcurrentqueue.NewFIFO()
+    sendQueue := goconcurrentqueue.NewFixedFIFO(sendQueueCapacity)
     resultsQueue := goconcurrentqueue.NewFIFO()
-    
-    go runChecks(sendQueue, resultsQueue)
-    queueChecks(sendQueue, checks, interval)
-    go handleResults(resultsQueue, domainStats, len(checks))
 
-    //Loop until done.
-    for{
-        
+    registry := NewRegistry(ctx, sendQueue)
+    for _, c := range cfg.Checks {
+        registry.Add(c)
     }
 
-    fmt.Println("Done")
+    metricsListen := cfg.Metrics.Listen
+    if metricsListen == "" {
+        metricsListen = defaultMetricsListen
+    }
+    metrics := NewMetricsServer()
+    metrics.Start(metricsListen)
+
+    coordinator := NewCoordinator(registry, quorum)
+    coordinator.Start(listen, tlsConfig)
+    go coordinator.pruneDeadWorkers(ctx)
+
+    adminListen := cfg.Admin.Listen
+    if adminListen == "" {
+        adminListen = defaultAdminListen
+    }
+    admin := NewAdminServer(registry, resultsQueue, configPath)
+    admin.onReload = coordinator.bumpChecksVersion
+    admin.Start(adminListen)
+
+    go runChecks(ctx, sendQueue, resultsQueue, registry, poolSize)
+    go coordinator.consumeLocalResults(ctx, registry, resultsQueue, metrics, localVantageName())
+    go coordinator.printLoop(ctx)
+
+    <-ctx.Done()
+    slog.Info("Shutting down coordinator")
 }
 
-func loadConfig(configPath string)([]Check) {
-    var checks []Check
-    
+// localVantageName labels results produced by the coordinator's own,
+// locally-run checks when reporting alongside remote workers.
+func localVantageName() string {
+    host, err := os.Hostname()
+    if err != nil || host == "" {
+        return "local"
+    }
+    return host
+}
+
+// loadMutualTLSConfig builds a tls.Config for mutual auth between the
+// coordinator and its workers. It returns a nil config (plain TCP/HTTP) if
+// no cert/key/CA were supplied.
+func loadMutualTLSConfig(certFile, keyFile, caFile string, isServer bool) (*tls.Config, error) {
+    if certFile == "" || keyFile == "" || caFile == "" {
+        return nil, nil
+    }
+
+    cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+    if err != nil {
+        return nil, fmt.Errorf("loading TLS keypair: %w", err)
+    }
+
+    caCert, err := os.ReadFile(caFile)
+    if err != nil {
+        return nil, fmt.Errorf("reading CA bundle: %w", err)
+    }
+    pool := x509.NewCertPool()
+    if !pool.AppendCertsFromPEM(caCert) {
+        return nil, fmt.Errorf("could not parse CA bundle %s", caFile)
+    }
+
+    cfg := &tls.Config{Certificates: []tls.Certificate{cert}, RootCAs: pool}
+    if isServer {
+        cfg.ClientCAs = pool
+        cfg.ClientAuth = tls.RequireAndVerifyClientCert
+    }
+    return cfg, nil
+}
+
+func loadConfig(configPath string) (Config, error) {
+    var cfg Config
+
     slog.Debug(fmt.Sprintf("Loading configuration: %s", configPath))
 
     yamlFile, err := os.ReadFile(configPath)
     if err != nil {
-        slog.Error("Error opening config", err)
-        panic(err)
+        return Config{}, fmt.Errorf("reading config %s: %w", configPath, err)
     }
 
-    err = yaml.Unmarshal(yamlFile, &checks)
-    if err != nil {
-        slog.Error("Error reading config", err)
-        panic(err)
+    if err := yaml.Unmarshal(yamlFile, &cfg); err != nil {
+        return Config{}, fmt.Errorf("parsing config %s: %w", configPath, err)
+    }
+
+    applyDefaults(cfg.Checks)
+
+    return cfg, nil
+}
+
+func applyDefaults(checks []Check) {
+    for i := range checks {
+        c := &checks[i]
+        if c.Type == "" {
+            c.Type = defaultCheckType
+        }
+        if c.Timeout.Duration == 0 {
+            c.Timeout.Duration = defaultTimeout
+        }
+        if c.Attempts == 0 {
+            c.Attempts = defaultAttempts
+        }
+        if c.RetryBackoff.Duration == 0 {
+            c.RetryBackoff.Duration = defaultRetryBackoff
+        }
+        if c.Interval.Duration == 0 {
+            c.Interval.Duration = defaultInterval
+        }
+    }
+}
+
+// resolveDomain buckets a check by domain, using the prober's advertised
+// target rather than assuming every check carries a URL.
+func resolveDomain(check Check) string {
+    target := check.URL
+    if prober, err := buildProber(check); err == nil {
+        target = prober.Target()
+    }
+    return getDomain(target)
+}
+
+// Registry holds the live set of checks: their ticker goroutines, and the
+// DomainStats accumulated from their results. It is the shared state the
+// admin HTTP API (run/list/reload/pause/resume) operates on.
+type Registry struct {
+    ctx context.Context
+    sendQueue goconcurrentqueue.Queue
+
+    mu sync.RWMutex
+    checks map[string]Check
+    cancels map[string]context.CancelFunc
+    paused bool
+
+    pendingMu sync.Mutex
+    pending map[string]bool
+
+    statsMu sync.Mutex
+    stats map[string]DomainStats
+}
+
+// NewRegistry builds a Registry whose per-check tickers are all cancelled
+// when ctx is done, in addition to their individual Remove/Pause paths.
+func NewRegistry(ctx context.Context, sendQueue goconcurrentqueue.Queue) *Registry {
+    return &Registry{
+        ctx: ctx,
+        sendQueue: sendQueue,
+        checks: make(map[string]Check),
+        cancels: make(map[string]context.CancelFunc),
+        pending: make(map[string]bool),
+        stats: make(map[string]DomainStats),
+    }
+}
+
+// Add resolves check's domain, stores it, and starts its ticker unless the
+// registry is currently paused.
+func (r *Registry) Add(check Check) {
+    check.Domain = resolveDomain(check)
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.checks[check.Name] = check
+
+    r.statsMu.Lock()
+    if _, ok := r.stats[check.Domain]; !ok {
+        r.stats[check.Domain] = DomainStats{DomainName: check.Domain}
+    }
+    r.statsMu.Unlock()
+
+    if !r.paused {
+        r.startTickerLocked(check)
     }
+}
 
-    return checks
+// Remove stops check's ticker, if any, and drops it from the registry.
+func (r *Registry) Remove(name string) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if cancel, ok := r.cancels[name]; ok {
+        cancel()
+        delete(r.cancels, name)
+    }
+    delete(r.checks, name)
 }
 
-func queueChecks(sendQueue goconcurrentqueue.Queue, checks []Check, interval time.Duration) {
-    ticker := time.NewTicker(interval)
-    done := make(chan bool)
+// Pause stops every check's ticker without forgetting the checks or stats.
+func (r *Registry) Pause() {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.paused {
+        return
+    }
+    r.paused = true
+    for name, cancel := range r.cancels {
+        cancel()
+        delete(r.cancels, name)
+    }
+}
 
+// Resume restarts a ticker for every registered check.
+func (r *Registry) Resume() {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if !r.paused {
+        return
+    }
+    r.paused = false
+    for _, check := range r.checks {
+        r.startTickerLocked(check)
+    }
+}
+
+func (r *Registry) startTickerLocked(check Check) {
+    ctx, cancel := context.WithCancel(r.ctx)
+    r.cancels[check.Name] = cancel
+    ticker := time.NewTicker(check.Interval.Duration)
     go func() {
-       for{
-           select {
-           case <-done:
-               return
-            case t := <-ticker.C:
-                _ = t
-                slog.Debug(fmt.Sprintf("Tick at", t))  
-                for _,c := range checks {
-                    sendQueue.Enqueue(c)
-                }
-           }
-       } 
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+                r.tryEnqueue(check)
+            }
+        }
+    }()
+}
+
+// tryEnqueue enqueues check for execution unless a previous tick for the
+// same check is still pending, in which case this tick is coalesced away.
+// If the queue is already full it drops the tick and logs rather than
+// growing the queue without bound.
+func (r *Registry) tryEnqueue(check Check) {
+    r.pendingMu.Lock()
+    if r.pending[check.Name] {
+        r.pendingMu.Unlock()
+        slog.Debug(fmt.Sprintf("Check %s is still pending from a previous tick, skipping", check.Name))
+        return
+    }
+    r.pending[check.Name] = true
+    r.pendingMu.Unlock()
+
+    if err := r.sendQueue.Enqueue(check); err != nil {
+        slog.Error(fmt.Sprintf("Dropping tick for %s, send queue is full: %v", check.Name, err))
+        r.pendingMu.Lock()
+        delete(r.pending, check.Name)
+        r.pendingMu.Unlock()
+    }
+}
+
+// MarkDone clears a check's pending flag once a worker goroutine has
+// finished executing it, allowing its next tick to be enqueued.
+func (r *Registry) MarkDone(name string) {
+    r.pendingMu.Lock()
+    delete(r.pending, name)
+    r.pendingMu.Unlock()
+}
+
+// Reload diffs newChecks against the running set: added checks start
+// ticking, removed checks stop, and checks whose config changed are
+// restarted without losing their accumulated DomainStats.
+func (r *Registry) Reload(newChecks []Check) {
+    seen := make(map[string]bool, len(newChecks))
+    for _, c := range newChecks {
+        seen[c.Name] = true
+        if existing, ok := r.Get(c.Name); ok {
+            // existing.Domain was resolved and stamped in by Add; c.Domain
+            // is always "" fresh off the YAML unmarshal (no yaml tag), so
+            // strip it from both sides before comparing or every check
+            // would look "changed" on every reload.
+            existingForCompare := existing
+            existingForCompare.Domain = ""
+            cForCompare := c
+            cForCompare.Domain = ""
+            if reflect.DeepEqual(existingForCompare, cForCompare) {
+                continue
+            }
+            r.Remove(c.Name)
+        }
+        r.Add(c)
+    }
+
+    for _, existing := range r.List() {
+        if !seen[existing.Name] {
+            r.Remove(existing.Name)
+        }
+    }
+}
+
+func (r *Registry) Get(name string) (Check, bool) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    c, ok := r.checks[name]
+    return c, ok
+}
+
+func (r *Registry) List() []Check {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    out := make([]Check, 0, len(r.checks))
+    for _, c := range r.checks {
+        out = append(out, c)
+    }
+    return out
+}
+
+// RecordResult folds result into the domain's running availability stats.
+func (r *Registry) RecordResult(result CheckResult) {
+    r.statsMu.Lock()
+    defer r.statsMu.Unlock()
+    st := r.stats[result.Domain]
+    st.DomainName = result.Domain
+    st.Total++
+    if result.Result {
+        st.Up++
+    }
+    r.stats[result.Domain] = st
+}
+
+func (r *Registry) StatsSnapshot() map[string]DomainStats {
+    r.statsMu.Lock()
+    defer r.statsMu.Unlock()
+    out := make(map[string]DomainStats, len(r.stats))
+    for k, v := range r.stats {
+        out[k] = v
+    }
+    return out
+}
+
+// AdminServer exposes on-demand runs, config reload, and pause/resume over
+// HTTP so operators don't have to restart the process to change behavior.
+type AdminServer struct {
+    registry *Registry
+    resultsQueue goconcurrentqueue.Queue
+    configPath string
+    // onReload, if set, is called after a successful /reload so other
+    // subsystems (e.g. the coordinator's worker-facing check list) can
+    // notice the change.
+    onReload func()
+}
+
+func NewAdminServer(registry *Registry, resultsQueue goconcurrentqueue.Queue, configPath string) *AdminServer {
+    return &AdminServer{registry: registry, resultsQueue: resultsQueue, configPath: configPath}
+}
+
+// Start serves the admin API on listen in the background.
+func (a *AdminServer) Start(listen string) {
+    mux := a.mux()
+    go func() {
+        if err := http.ListenAndServe(listen, mux); err != nil {
+            slog.Error(fmt.Sprintf("Admin server stopped: %v", err))
+        }
     }()
 }
 
-func groupIntoDomains(checks []Check)(map[string]DomainStats) {
-    groups := make(map[string]DomainStats)
-    for index,c := range checks{
-        cDomain := getDomain(c.URL)
-        c.Domain = cDomain
-        checks[index] = c
-        if _, ok := groups[cDomain]; !ok {
-            groups[cDomain] = DomainStats{Up: 0, Total: 0, DomainName: cDomain}
-        } 
+// mux builds the admin API route table. Split out from Start so tests can
+// drive the handlers through a real HTTP round trip via httptest.Server
+// without binding a listener.
+func (a *AdminServer) mux() *http.ServeMux {
+    mux := http.NewServeMux()
+    mux.HandleFunc("POST /checks/{name}/run", a.handleRun)
+    mux.HandleFunc("GET /checks", a.handleList)
+    mux.HandleFunc("POST /reload", a.handleReload)
+    mux.HandleFunc("POST /pause", a.handlePause)
+    mux.HandleFunc("POST /resume", a.handleResume)
+    return mux
+}
+
+func (a *AdminServer) handleRun(w http.ResponseWriter, r *http.Request) {
+    name := r.PathValue("name")
+    check, ok := a.registry.Get(name)
+    if !ok {
+        http.Error(w, fmt.Sprintf("unknown check %q", name), http.StatusNotFound)
+        return
+    }
+
+    result, stats := executeCheck(check)
+    cr := CheckResult{OriginalCheck: check, Domain: check.Domain, Result: result, Stats: stats}
+    a.resultsQueue.Enqueue(cr)
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(cr)
+}
+
+func (a *AdminServer) handleList(w http.ResponseWriter, r *http.Request) {
+    resp := struct {
+        Checks []Check `json:"checks"`
+        Stats map[string]DomainStats `json:"stats"`
+    }{
+        Checks: a.registry.List(),
+        Stats: a.registry.StatsSnapshot(),
     }
-    return groups
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(resp)
 }
 
-func getDomain(fullurl string)(domain string) {
-    url, err := url.Parse(fullurl)
+func (a *AdminServer) handleReload(w http.ResponseWriter, r *http.Request) {
+    cfg, err := loadConfig(a.configPath)
     if err != nil {
-        slog.Error("Error parsing url", err)
-        panic(err)
+        slog.Error(fmt.Sprintf("Reload failed: %v", err))
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusBadRequest)
+        json.NewEncoder(w).Encode(struct {
+            Error string `json:"error"`
+        }{Error: err.Error()})
+        return
+    }
+    a.registry.Reload(cfg.Checks)
+    if a.onReload != nil {
+        a.onReload()
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *AdminServer) handlePause(w http.ResponseWriter, r *http.Request) {
+    a.registry.Pause()
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *AdminServer) handleResume(w http.ResponseWriter, r *http.Request) {
+    a.registry.Resume()
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// getDomain extracts the registrable domain from a URL, a host:port pair,
+// or a bare hostname.
+func getDomain(target string)(domain string) {
+    host := target
+    if u, err := url.Parse(target); err == nil && u.Hostname() != "" {
+        host = u.Hostname()
+    } else if h, _, err := net.SplitHostPort(target); err == nil {
+        host = h
+    }
+
+    parts := strings.Split(host, ".")
+    if len(parts) < 2 {
+        return host
     }
-    parts := strings.Split(url.Hostname(), ".")
     domain = parts[len(parts)-2] + "." + parts[len(parts)-1]
     return domain
 }
 
-func runChecks(sendQueue, resultQueue goconcurrentqueue.Queue) {
-    for {
-        value, _ := sendQueue.DequeueOrWaitForNextElement()
-        check := value.(Check)
-        result := sendRequest(check)
-        resultQueue.Enqueue(CheckResult{OriginalCheck: check, Result: result, Domain: check.Domain})
+// runChecks drains sendQueue with a bounded pool of poolSize goroutines,
+// rather than a single serial worker, so a slow check can't stall the
+// others behind it. registry may be nil (e.g. a worker that doesn't
+// coalesce ticks itself); when set, MarkDone is called once a check
+// finishes so its next tick can be enqueued.
+func runChecks(ctx context.Context, sendQueue, resultQueue goconcurrentqueue.Queue, registry *Registry, poolSize int) {
+    if poolSize < 1 {
+        poolSize = defaultWorkerPoolSize
     }
+
+    var wg sync.WaitGroup
+    for i := 0; i < poolSize; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for {
+                value, err := sendQueue.DequeueOrWaitForNextElementContext(ctx)
+                if err != nil {
+                    return
+                }
+                check := value.(Check)
+                result, stats := executeCheck(check)
+                resultQueue.Enqueue(CheckResult{OriginalCheck: check, Result: result, Domain: check.Domain, Stats: stats})
+                if registry != nil {
+                    registry.MarkDone(check.Name)
+                }
+            }
+        }()
+    }
+    wg.Wait()
+}
+
+// executeCheck builds the prober for check's type and retries it up to
+// check.Attempts times, doubling the delay between attempts (capped at
+// maxRetryBackoff) after each failure. The check counts as up if any
+// single attempt succeeds within the check's own timeout. It returns the
+// ProbeStats of whichever attempt decided the result.
+func executeCheck(check Check)(status bool, stats ProbeStats) {
+    prober, err := buildProber(check)
+    if err != nil {
+        slog.Error(fmt.Sprintf("Cannot run check %s: %v", check.Name, err))
+        return false, ProbeStats{}
+    }
+
+    backoff := check.RetryBackoff.Duration
+
+    for attempt := 1; attempt <= check.Attempts; attempt++ {
+        slog.Debug(fmt.Sprintf("Probing %s (%s)", check.Name, check.Type))
+
+        ctx, cncl := context.WithTimeout(context.Background(), check.Timeout.Duration)
+        up, attemptStats, err := prober.Probe(ctx)
+        cncl()
+        stats = attemptStats
+
+        if err != nil {
+            slog.Debug(fmt.Sprintf("Attempt %d/%d failed for %s: %v", attempt, check.Attempts, check.Name, err))
+        } else if up {
+            return true, stats
+        }
+
+        if attempt < check.Attempts {
+            time.Sleep(backoff)
+            backoff *= 2
+            if backoff > maxRetryBackoff {
+                backoff = maxRetryBackoff
+            }
+        }
+    }
+
+    return false, stats
 }
 
-func sendRequest(check Check)(status bool) {
-    slog.Debug(fmt.Sprintf("Sending message: %s", check.Name))
+// httpProber issues a single HTTP request and counts the check as up if
+// the response is a 2xx within the check's timeout.
+type httpProber struct {
+    check Check
+}
 
-    //Set timeout to 500ms
-    ctx, cncl := context.WithTimeout(context.Background(), time.Millisecond*500)
-    defer cncl()
-    
-    req, _ := http.NewRequestWithContext(ctx, check.Method, check.URL, nil)
+func newHTTPProber(check Check) (Prober, error) {
+    if check.URL == "" {
+        return nil, fmt.Errorf("http check %s requires a url", check.Name)
+    }
+    return &httpProber{check: check}, nil
+}
+
+func (p *httpProber) Target() string { return p.check.URL }
+
+func (p *httpProber) Probe(ctx context.Context) (bool, ProbeStats, error) {
+    req, err := http.NewRequestWithContext(ctx, p.check.Method, p.check.URL, nil)
+    if err != nil {
+        return false, ProbeStats{}, err
+    }
 
-    var start, connect, dns, tlsHandshake time.Time
+    var stats ProbeStats
+    var start, dnsStart, connectStart, tlsStart time.Time
 
     trace := &httptrace.ClientTrace{
-        DNSStart: func(dsi httptrace.DNSStartInfo) { dns = time.Now() },
-        DNSDone: func(ddi httptrace.DNSDoneInfo) {
-            slog.Debug(fmt.Sprintf("DNS Done: %v", time.Since(dns)))
-        },
+        DNSStart: func(dsi httptrace.DNSStartInfo) { dnsStart = time.Now() },
+        DNSDone: func(ddi httptrace.DNSDoneInfo) { stats.DNS = time.Since(dnsStart) },
 
-        TLSHandshakeStart: func() { tlsHandshake = time.Now() },
-        TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
-            slog.Debug(fmt.Sprintf("TLS Handshake: %v", time.Since(tlsHandshake)))
-        },
+        TLSHandshakeStart: func() { tlsStart = time.Now() },
+        TLSHandshakeDone: func(cs tls.ConnectionState, err error) { stats.TLSHandshake = time.Since(tlsStart) },
 
-        ConnectStart: func(network, addr string) { connect = time.Now() },
-        ConnectDone: func(network, addr string, err error) {
-            slog.Debug(fmt.Sprintf("Connect time: %v", time.Since(connect)))
-        },
+        ConnectStart: func(network, addr string) { connectStart = time.Now() },
+        ConnectDone: func(network, addr string, err error) { stats.Connect = time.Since(connectStart) },
 
-        GotFirstResponseByte: func() {
-            slog.Debug(fmt.Sprintf("Time from start to first byte: %v", time.Since(start)))
-        },
+        GotFirstResponseByte: func() { stats.TTFB = time.Since(start) },
     }
 
     req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
     start = time.Now()
 
-    resp, _ := http.DefaultTransport.RoundTrip(req)
-    
-    totalTime := time.Since(start)
-    timeout := time.Duration(500)*time.Millisecond
+    resp, err := http.DefaultTransport.RoundTrip(req)
+    stats.Total = time.Since(start)
+    if err != nil {
+        return false, stats, err
+    }
+    defer resp.Body.Close()
+
+    stats.StatusCode = resp.StatusCode
+    up := resp.StatusCode >= 200 && resp.StatusCode < 300 && stats.Total < p.check.Timeout.Duration
+    return up, stats, nil
+}
 
-    finalresult := false
-    if resp.StatusCode == 200 && totalTime < timeout {
-        finalresult = true
+// tcpProber dials the target and counts the check as up if the connection
+// succeeds within the context's deadline.
+type tcpProber struct {
+    address string
+}
+
+func newTCPProber(check Check) (Prober, error) {
+    if check.Host == "" || check.Port == 0 {
+        return nil, fmt.Errorf("tcp check %s requires host and port", check.Name)
     }
+    return &tcpProber{address: net.JoinHostPort(check.Host, strconv.Itoa(check.Port))}, nil
+}
+
+func (p *tcpProber) Target() string { return p.address }
+
+func (p *tcpProber) Probe(ctx context.Context) (bool, ProbeStats, error) {
+    start := time.Now()
+    var d net.Dialer
+    conn, err := d.DialContext(ctx, "tcp", p.address)
+    stats := ProbeStats{Connect: time.Since(start), Total: time.Since(start)}
+    if err != nil {
+        return false, stats, err
+    }
+    conn.Close()
+    return true, stats, nil
+}
+
+// tlsProber completes a TLS handshake with the target and marks the check
+// down if the leaf certificate expires within expiryWindow.
+type tlsProber struct {
+    address string
+    expiryWindow time.Duration
+}
 
-    return finalresult
+func newTLSProber(check Check) (Prober, error) {
+    if check.Host == "" {
+        return nil, fmt.Errorf("tls check %s requires a host", check.Name)
+    }
+    port := check.Port
+    if port == 0 {
+        port = defaultTLSPort
+    }
+    window := check.CertExpiryWindow.Duration
+    if window == 0 {
+        window = defaultCertExpiryWindow
+    }
+    return &tlsProber{address: net.JoinHostPort(check.Host, strconv.Itoa(port)), expiryWindow: window}, nil
 }
 
-func handleResults(resultQueue goconcurrentqueue.Queue, stats map[string]DomainStats, round int) {
-    for{
-        for range round {
-            value, _ := resultQueue.DequeueOrWaitForNextElement()
-            result := value.(CheckResult)
+func (p *tlsProber) Target() string { return p.address }
 
-            st := stats[result.Domain]
-            st.Total++
-            if result.Result {
-                st.Up++
+func (p *tlsProber) Probe(ctx context.Context) (bool, ProbeStats, error) {
+    start := time.Now()
+    d := tls.Dialer{}
+    conn, err := d.DialContext(ctx, "tcp", p.address)
+    total := time.Since(start)
+    if err != nil {
+        return false, ProbeStats{Total: total}, err
+    }
+    defer conn.Close()
+
+    tlsConn, ok := conn.(*tls.Conn)
+    if !ok {
+        return false, ProbeStats{Total: total}, fmt.Errorf("tls dial for %s did not return a tls connection", p.address)
+    }
+
+    certs := tlsConn.ConnectionState().PeerCertificates
+    if len(certs) == 0 {
+        return false, ProbeStats{Total: total}, fmt.Errorf("no peer certificates presented by %s", p.address)
+    }
+
+    expiry := certs[0].NotAfter
+    up := certExpiryOK(expiry, p.expiryWindow)
+    return up, ProbeStats{Total: total, TLSHandshake: total, CertExpiry: expiry}, nil
+}
+
+// certExpiryOK reports whether a leaf certificate expiring at expiry is
+// still far enough out to count as "up", given the check's expiryWindow.
+func certExpiryOK(expiry time.Time, expiryWindow time.Duration) bool {
+    return time.Until(expiry) > expiryWindow
+}
+
+// dnsProber resolves name and, if ExpectedAnswer is set, asserts the
+// resolved records contain it.
+type dnsProber struct {
+    name string
+    recordType string
+    expected string
+    resolver *net.Resolver
+}
+
+func newDNSProber(check Check) (Prober, error) {
+    if check.Host == "" {
+        return nil, fmt.Errorf("dns check %s requires a host", check.Name)
+    }
+    recordType := strings.ToUpper(check.RecordType)
+    if recordType == "" {
+        recordType = "A"
+    }
+    return &dnsProber{name: check.Host, recordType: recordType, expected: check.ExpectedAnswer, resolver: net.DefaultResolver}, nil
+}
+
+func (p *dnsProber) Target() string { return p.name }
+
+func (p *dnsProber) Probe(ctx context.Context) (bool, ProbeStats, error) {
+    start := time.Now()
+
+    var answers []string
+    var err error
+
+    switch p.recordType {
+    case "A", "AAAA":
+        var addrs []net.IPAddr
+        addrs, err = p.resolver.LookupIPAddr(ctx, p.name)
+        for _, addr := range addrs {
+            isV4 := addr.IP.To4() != nil
+            if (p.recordType == "A") == isV4 {
+                answers = append(answers, addr.IP.String())
             }
-            stats[result.Domain] = st
         }
+    case "CNAME":
+        var cname string
+        cname, err = p.resolver.LookupCNAME(ctx, p.name)
+        if err == nil {
+            answers = []string{cname}
+        }
+    case "TXT":
+        answers, err = p.resolver.LookupTXT(ctx, p.name)
+    default:
+        err = fmt.Errorf("unsupported dns record type %q", p.recordType)
+    }
+
+    stats := ProbeStats{DNS: time.Since(start), Total: time.Since(start)}
+    if err != nil {
+        return false, stats, err
+    }
+    if len(answers) == 0 {
+        return false, stats, fmt.Errorf("no %s records found for %s", p.recordType, p.name)
+    }
+    if p.expected == "" {
+        return true, stats, nil
+    }
+    for _, answer := range answers {
+        if strings.EqualFold(strings.TrimSuffix(answer, "."), strings.TrimSuffix(p.expected, ".")) {
+            return true, stats, nil
+        }
+    }
+    return false, stats, fmt.Errorf("expected %s record %q for %s, got %v", p.recordType, p.expected, p.name, answers)
+}
+
+// icmpProber shells out to the system ping binary rather than opening a
+// raw socket, so it works without elevated privileges.
+type icmpProber struct {
+    host string
+    rttThreshold time.Duration
+}
+
+func newICMPProber(check Check) (Prober, error) {
+    if check.Host == "" {
+        return nil, fmt.Errorf("icmp check %s requires a host", check.Name)
+    }
+    threshold := check.RTTThreshold.Duration
+    if threshold == 0 {
+        threshold = defaultRTTThreshold
+    }
+    return &icmpProber{host: check.Host, rttThreshold: threshold}, nil
+}
+
+func (p *icmpProber) Target() string { return p.host }
 
-        for domain,s := range stats {
-            availability := 100.00* (float64(s.Up) / float64(s.Total))
-            fmt.Printf("%v has %.0f%% availability percentage\n", domain, availability)
-            slog.Debug(fmt.Sprintf("%v Up: %v Total: %v", domain, s.Up, s.Total))
+func (p *icmpProber) Probe(ctx context.Context) (bool, ProbeStats, error) {
+    timeoutSec := 1
+    if deadline, ok := ctx.Deadline(); ok {
+        if secs := int(time.Until(deadline).Seconds()); secs > timeoutSec {
+            timeoutSec = secs
         }
     }
+
+    start := time.Now()
+    output, err := exec.CommandContext(ctx, "ping", "-c", "1", "-W", strconv.Itoa(timeoutSec), p.host).Output()
+    total := time.Since(start)
+    if err != nil {
+        return false, ProbeStats{Total: total}, err
+    }
+
+    rtt, err := parsePingRTT(output)
+    if err != nil {
+        return false, ProbeStats{Total: total}, err
+    }
+
+    return rtt <= p.rttThreshold, ProbeStats{Total: total, RTT: rtt}, nil
+}
+
+func parsePingRTT(output []byte) (time.Duration, error) {
+    marker := "time="
+    idx := strings.Index(string(output), marker)
+    if idx == -1 {
+        return 0, fmt.Errorf("could not find round-trip time in ping output")
+    }
+    rest := string(output)[idx+len(marker):]
+    end := strings.IndexAny(rest, " \n")
+    if end == -1 {
+        end = len(rest)
+    }
+    millis, err := strconv.ParseFloat(rest[:end], 64)
+    if err != nil {
+        return 0, fmt.Errorf("could not parse round-trip time: %w", err)
+    }
+    return time.Duration(millis * float64(time.Millisecond)), nil
+}
+
+// execProber treats a non-zero exit from Script as down.
+type execProber struct {
+    script string
+    args []string
+}
+
+func newExecProber(check Check) (Prober, error) {
+    if check.Script == "" {
+        return nil, fmt.Errorf("exec check %s requires a script", check.Name)
+    }
+    return &execProber{script: check.Script, args: check.Args}, nil
+}
+
+func (p *execProber) Target() string { return p.script }
+
+func (p *execProber) Probe(ctx context.Context) (bool, ProbeStats, error) {
+    start := time.Now()
+    err := exec.CommandContext(ctx, p.script, p.args...).Run()
+    stats := ProbeStats{Total: time.Since(start)}
+    if err != nil {
+        return false, stats, err
+    }
+    return true, stats, nil
+}
+
+// VantageReport is what a worker posts back to the coordinator: the
+// results a single vantage point observed for one or more checks.
+type VantageReport struct {
+    WorkerID string `json:"worker_id"`
+    Vantage string `json:"vantage"`
+    Results []CheckResult `json:"results"`
+}
+
+type registeredWorker struct {
+    id string
+    vantage string
+    lastHeartbeat time.Time
+}
+
+// aggregatedVantage is the key Coordinator stores the quorum-merged
+// availability for a domain under, alongside each real vantage point's own
+// DomainStats.
+const aggregatedVantage = "aggregated"
+
+// Coordinator merges CheckResults reported by the coordinator's own local
+// checks and by remote workers, tracking per-vantage DomainStats plus a
+// quorum-merged "aggregated" DomainStats per domain.
+type Coordinator struct {
+    registry *Registry
+    quorum int
+
+    mu sync.Mutex
+    workers map[string]*registeredWorker
+    checksVersion int
+
+    voteMu sync.Mutex
+    votes map[string]map[string]bool // check name -> vantage -> up
+
+    statsMu sync.Mutex
+    vantageStats map[string]map[string]DomainStats // domain -> vantage -> stats
+}
+
+func NewCoordinator(registry *Registry, quorum int) *Coordinator {
+    if quorum < 1 {
+        quorum = 1
+    }
+    return &Coordinator{
+        registry: registry,
+        quorum: quorum,
+        workers: make(map[string]*registeredWorker),
+        // checksVersion starts at 1, not 0: a worker's first long-poll
+        // always sends since=0, and 0 must never be mistaken for "caller
+        // is already current" or the worker would block out its initial
+        // check list forever.
+        checksVersion: 1,
+        votes: make(map[string]map[string]bool),
+        vantageStats: make(map[string]map[string]DomainStats),
+    }
+}
+
+// Start serves the worker-facing API (registration, heartbeat, check
+// distribution, result ingestion) on listen in the background.
+func (c *Coordinator) Start(listen string, tlsConfig *tls.Config) {
+    mux := c.mux()
+
+    server := &http.Server{Addr: listen, Handler: mux, TLSConfig: tlsConfig}
+    go func() {
+        var err error
+        if tlsConfig != nil {
+            err = server.ListenAndServeTLS("", "")
+        } else {
+            err = server.ListenAndServe()
+        }
+        if err != nil && err != http.ErrServerClosed {
+            slog.Error(fmt.Sprintf("Coordinator server stopped: %v", err))
+        }
+    }()
+}
+
+// mux builds the worker-facing route table. Split out from Start so tests
+// can drive the handlers through a real HTTP round trip via
+// httptest.Server without binding a listener or mTLS.
+func (c *Coordinator) mux() *http.ServeMux {
+    mux := http.NewServeMux()
+    mux.HandleFunc("POST /workers/register", c.handleRegister)
+    mux.HandleFunc("POST /workers/{id}/heartbeat", c.handleHeartbeat)
+    mux.HandleFunc("GET /workers/{id}/checks", c.handleChecksLongPoll)
+    mux.HandleFunc("POST /workers/{id}/results", c.handleReport)
+    return mux
+}
+
+func (c *Coordinator) handleRegister(w http.ResponseWriter, r *http.Request) {
+    var req struct {
+        WorkerID string `json:"worker_id"`
+        Vantage string `json:"vantage"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    if req.WorkerID == "" {
+        http.Error(w, "worker_id is required", http.StatusBadRequest)
+        return
+    }
+
+    c.mu.Lock()
+    c.workers[req.WorkerID] = &registeredWorker{id: req.WorkerID, vantage: req.Vantage, lastHeartbeat: time.Now()}
+    c.mu.Unlock()
+
+    slog.Debug(fmt.Sprintf("Registered worker %s (vantage %s)", req.WorkerID, req.Vantage))
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Coordinator) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+    id := r.PathValue("id")
+
+    c.mu.Lock()
+    worker, ok := c.workers[id]
+    if ok {
+        worker.lastHeartbeat = time.Now()
+    }
+    c.mu.Unlock()
+
+    if !ok {
+        http.Error(w, fmt.Sprintf("unknown worker %q", id), http.StatusNotFound)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// handleChecksLongPoll blocks until the check list's version advances past
+// the caller's "since" or workerLongPollTimeout elapses, then returns the
+// current version and check list.
+func (c *Coordinator) handleChecksLongPoll(w http.ResponseWriter, r *http.Request) {
+    since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+    deadline := time.Now().Add(workerLongPollTimeout)
+
+    for {
+        version, checks := c.checksSnapshot()
+        if version != since || time.Now().After(deadline) {
+            w.Header().Set("Content-Type", "application/json")
+            json.NewEncoder(w).Encode(struct {
+                Version int `json:"version"`
+                Checks []Check `json:"checks"`
+            }{Version: version, Checks: checks})
+            return
+        }
+
+        select {
+        case <-r.Context().Done():
+            return
+        case <-time.After(workerLongPollInterval):
+        }
+    }
+}
+
+func (c *Coordinator) handleReport(w http.ResponseWriter, r *http.Request) {
+    var report VantageReport
+    if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    report.WorkerID = r.PathValue("id")
+
+    c.Ingest(report)
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Coordinator) checksSnapshot() (int, []Check) {
+    c.mu.Lock()
+    version := c.checksVersion
+    c.mu.Unlock()
+    return version, c.registry.List()
+}
+
+// bumpChecksVersion wakes any workers long-polling /workers/{id}/checks so
+// they pick up a config reload.
+func (c *Coordinator) bumpChecksVersion() {
+    c.mu.Lock()
+    c.checksVersion++
+    c.mu.Unlock()
+}
+
+func (c *Coordinator) pruneDeadWorkers(ctx context.Context) {
+    ticker := time.NewTicker(workerHeartbeatInterval)
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            c.mu.Lock()
+            for id, worker := range c.workers {
+                if time.Since(worker.lastHeartbeat) > workerHeartbeatTimeout {
+                    delete(c.workers, id)
+                    slog.Debug(fmt.Sprintf("Pruned stale worker %s (vantage %s)", id, worker.vantage))
+                    c.clearVotesForVantage(worker.vantage)
+                }
+            }
+            c.mu.Unlock()
+        }
+    }
+}
+
+// clearVotesForVantage drops a vantage point's last-reported vote for
+// every check so a pruned worker's stale "up" can't keep counting toward
+// quorum after it has gone dark.
+func (c *Coordinator) clearVotesForVantage(vantage string) {
+    c.voteMu.Lock()
+    defer c.voteMu.Unlock()
+    for _, votes := range c.votes {
+        delete(votes, vantage)
+    }
+}
+
+// Ingest folds a vantage point's results into its own per-vantage
+// DomainStats and into the quorum-merged "aggregated" DomainStats: a check
+// counts as up in aggregate once at least quorum vantage points report its
+// most recent result as up. The aggregated DomainStats.Total is bumped
+// once per call to Ingest (i.e. per vantage report), not per synchronized
+// round across vantage points; see the DomainStats doc comment for why.
+func (c *Coordinator) Ingest(report VantageReport) {
+    for _, result := range report.Results {
+        c.recordVantageStat(result.Domain, report.Vantage, result.Result)
+
+        c.voteMu.Lock()
+        if c.votes[result.OriginalCheck.Name] == nil {
+            c.votes[result.OriginalCheck.Name] = make(map[string]bool)
+        }
+        c.votes[result.OriginalCheck.Name][report.Vantage] = result.Result
+        upVotes := 0
+        for _, up := range c.votes[result.OriginalCheck.Name] {
+            if up {
+                upVotes++
+            }
+        }
+        quorumUp := upVotes >= c.quorum
+        c.voteMu.Unlock()
+
+        c.recordVantageStat(result.Domain, aggregatedVantage, quorumUp)
+    }
+}
+
+func (c *Coordinator) recordVantageStat(domain, vantage string, up bool) {
+    c.statsMu.Lock()
+    defer c.statsMu.Unlock()
+
+    if c.vantageStats[domain] == nil {
+        c.vantageStats[domain] = make(map[string]DomainStats)
+    }
+    st := c.vantageStats[domain][vantage]
+    st.DomainName = domain
+    st.Total++
+    if up {
+        st.Up++
+    }
+    c.vantageStats[domain][vantage] = st
+}
+
+// consumeLocalResults feeds the coordinator's own locally-run checks into
+// the same pipeline remote workers report through, tagged with vantage.
+func (c *Coordinator) consumeLocalResults(ctx context.Context, registry *Registry, resultQueue goconcurrentqueue.Queue, metrics *MetricsServer, vantage string) {
+    for {
+        value, err := resultQueue.DequeueOrWaitForNextElementContext(ctx)
+        if err != nil {
+            return
+        }
+        result := value.(CheckResult)
+
+        registry.RecordResult(result)
+        metrics.Observe(result)
+        c.Ingest(VantageReport{Vantage: vantage, Results: []CheckResult{result}})
+    }
+}
+
+// printLoop periodically prints each domain's availability, both per
+// vantage point and the quorum-merged aggregate.
+func (c *Coordinator) printLoop(ctx context.Context) {
+    ticker := time.NewTicker(defaultInterval)
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            c.statsMu.Lock()
+            for domain, byVantage := range c.vantageStats {
+                for vantage, s := range byVantage {
+                    if s.Total == 0 {
+                        continue
+                    }
+                    availability := 100.00 * (float64(s.Up) / float64(s.Total))
+                    if vantage == aggregatedVantage {
+                        fmt.Printf("%v has %.0f%% aggregated availability (quorum %d)\n", domain, availability, c.quorum)
+                    } else {
+                        fmt.Printf("%v has %.0f%% availability from vantage %s\n", domain, availability, vantage)
+                    }
+                }
+            }
+            c.statsMu.Unlock()
+        }
+    }
+}
+
+// runWorker registers with the coordinator, then loops fetching the check
+// list (long-poll) and streaming results back until killed.
+// runWorker blocks until ctx is cancelled (SIGINT/SIGTERM), at which point
+// its sendQueue/resultsQueue consumers and long-poll loop all drain out.
+func runWorker(ctx context.Context, coordinatorAddr, vantage string, poolSize int, tlsConfig *tls.Config) {
+    if coordinatorAddr == "" {
+        slog.Error("worker role requires --coordinator-addr")
+        os.Exit(1)
+    }
+    if vantage == "" {
+        vantage = localVantageName()
+    }
+    workerID := fmt.Sprintf("%s-%d", vantage, os.Getpid())
+
+    client := &http.Client{Timeout: workerLongPollTimeout + 5*time.Second}
+    if tlsConfig != nil {
+        client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+    }
+
+    if err := registerWithCoordinator(client, coordinatorAddr, workerID, vantage); err != nil {
+        slog.Error(fmt.Sprintf("Could not register with coordinator: %v", err))
+        os.Exit(1)
+    }
+    go sendHeartbeats(ctx, client, coordinatorAddr, workerID)
+
+    sendQueue := goconcurrentqueue.NewFixedFIFO(sendQueueCapacity)
+    resultsQueue := goconcurrentqueue.NewFIFO()
+    registry := NewRegistry(ctx, sendQueue)
+
+    go runChecks(ctx, sendQueue, resultsQueue, registry, poolSize)
+    go reportResults(ctx, client, coordinatorAddr, workerID, vantage, resultsQueue)
+
+    version := 0
+    for {
+        select {
+        case <-ctx.Done():
+            slog.Info("Shutting down worker")
+            return
+        default:
+        }
+        newVersion, checks, err := fetchChecks(ctx, client, coordinatorAddr, workerID, version)
+        if err != nil {
+            if ctx.Err() != nil {
+                continue
+            }
+            slog.Error(fmt.Sprintf("Could not fetch checks from coordinator: %v", err))
+            time.Sleep(workerLongPollInterval)
+            continue
+        }
+        if newVersion != version {
+            applyDefaults(checks)
+            registry.Reload(checks)
+            version = newVersion
+        }
+    }
+}
+
+func registerWithCoordinator(client *http.Client, addr, workerID, vantage string) error {
+    body, err := json.Marshal(struct {
+        WorkerID string `json:"worker_id"`
+        Vantage string `json:"vantage"`
+    }{WorkerID: workerID, Vantage: vantage})
+    if err != nil {
+        return err
+    }
+
+    resp, err := client.Post(addr+"/workers/register", "application/json", bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusNoContent {
+        return fmt.Errorf("registration failed: %s", resp.Status)
+    }
+    return nil
+}
+
+func sendHeartbeats(ctx context.Context, client *http.Client, addr, workerID string) {
+    ticker := time.NewTicker(workerHeartbeatInterval)
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            resp, err := client.Post(fmt.Sprintf("%s/workers/%s/heartbeat", addr, workerID), "application/json", nil)
+            if err != nil {
+                slog.Debug(fmt.Sprintf("Heartbeat failed: %v", err))
+                continue
+            }
+            resp.Body.Close()
+        }
+    }
+}
+
+func fetchChecks(ctx context.Context, client *http.Client, addr, workerID string, since int) (int, []Check, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/workers/%s/checks?since=%d", addr, workerID, since), nil)
+    if err != nil {
+        return since, nil, err
+    }
+    resp, err := client.Do(req)
+    if err != nil {
+        return since, nil, err
+    }
+    defer resp.Body.Close()
+
+    var payload struct {
+        Version int `json:"version"`
+        Checks []Check `json:"checks"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+        return since, nil, err
+    }
+    return payload.Version, payload.Checks, nil
+}
+
+func reportResults(ctx context.Context, client *http.Client, addr, workerID, vantage string, resultsQueue goconcurrentqueue.Queue) {
+    for {
+        value, err := resultsQueue.DequeueOrWaitForNextElementContext(ctx)
+        if err != nil {
+            return
+        }
+        result := value.(CheckResult)
+
+        body, err := json.Marshal(VantageReport{WorkerID: workerID, Vantage: vantage, Results: []CheckResult{result}})
+        if err != nil {
+            slog.Error(fmt.Sprintf("Could not marshal result for %s: %v", result.OriginalCheck.Name, err))
+            continue
+        }
+
+        resp, err := client.Post(fmt.Sprintf("%s/workers/%s/results", addr, workerID), "application/json", bytes.NewReader(body))
+        if err != nil {
+            slog.Debug(fmt.Sprintf("Could not report result for %s: %v", result.OriginalCheck.Name, err))
+            continue
+        }
+        resp.Body.Close()
+    }
+}
+
+// MetricsServer exposes a Prometheus /metrics endpoint with gauges and
+// histograms for the most recent result of every check.
+type MetricsServer struct {
+    registry *prometheus.Registry
+    up *prometheus.GaugeVec
+    duration *prometheus.HistogramVec
+    statusCode *prometheus.GaugeVec
+    certExpiry *prometheus.GaugeVec
+}
+
+func NewMetricsServer() *MetricsServer {
+    m := &MetricsServer{
+        registry: prometheus.NewRegistry(),
+        up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+            Name: "healthcheck_up",
+            Help: "Whether the most recent check attempt succeeded (1) or failed (0).",
+        }, []string{"name", "domain"}),
+        duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+            Name: "healthcheck_duration_seconds",
+            Help: "Duration of a check attempt, broken out by phase.",
+        }, []string{"name", "domain", "phase"}),
+        statusCode: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+            Name: "healthcheck_status_code",
+            Help: "HTTP status code returned by the most recent attempt.",
+        }, []string{"name", "domain"}),
+        certExpiry: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+            Name: "healthcheck_tls_cert_expiry_seconds",
+            Help: "Seconds until the probed TLS certificate expires.",
+        }, []string{"name", "domain"}),
+    }
+
+    m.registry.MustRegister(m.up, m.duration, m.statusCode, m.certExpiry)
+    return m
+}
+
+// Start serves /metrics on listen in the background.
+func (m *MetricsServer) Start(listen string) {
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+    go func() {
+        if err := http.ListenAndServe(listen, mux); err != nil {
+            slog.Error(fmt.Sprintf("Metrics server stopped: %v", err))
+        }
+    }()
+}
+
+// Observe records a CheckResult's phase timings and outcome.
+func (m *MetricsServer) Observe(result CheckResult) {
+    labels := prometheus.Labels{"name": result.OriginalCheck.Name, "domain": result.Domain}
+
+    upValue := 0.0
+    if result.Result {
+        upValue = 1.0
+    }
+    m.up.With(labels).Set(upValue)
+
+    phase := func(name string, d time.Duration) {
+        if d <= 0 {
+            return
+        }
+        m.duration.With(prometheus.Labels{"name": result.OriginalCheck.Name, "domain": result.Domain, "phase": name}).Observe(d.Seconds())
+    }
+    phase("dns", result.Stats.DNS)
+    phase("connect", result.Stats.Connect)
+    phase("tls", result.Stats.TLSHandshake)
+    phase("ttfb", result.Stats.TTFB)
+    phase("total", result.Stats.Total)
+
+    if result.Stats.StatusCode != 0 {
+        m.statusCode.With(labels).Set(float64(result.Stats.StatusCode))
+    }
+    if !result.Stats.CertExpiry.IsZero() {
+        m.certExpiry.With(labels).Set(time.Until(result.Stats.CertExpiry).Seconds())
+    }
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+  "context"
+  "testing"
+  "time"
+
+  "github.com/enriquebris/goconcurrentqueue"
+)
+
+func newTestRegistry() *Registry {
+  return NewRegistry(context.Background(), goconcurrentqueue.NewFixedFIFO(16))
+}
+
+func tcpCheck(name, host string, port int) Check {
+  return Check{Name: name, Type: "tcp", Host: host, Port: port, Interval: Duration{time.Hour}}
+}
+
+func TestRegistryReloadAddsRemovesAndUpdates(t *testing.T) {
+  r := newTestRegistry()
+  r.Add(tcpCheck("a", "a.example.com", 80))
+  r.Add(tcpCheck("b", "b.example.com", 80))
+
+  r.Reload([]Check{
+    tcpCheck("a", "a.example.com", 80),
+    tcpCheck("c", "c.example.com", 80),
+  })
+
+  if _, ok := r.Get("a"); !ok {
+    t.Error("unchanged check a should survive Reload")
+  }
+  if _, ok := r.Get("b"); ok {
+    t.Error("check b dropped from the new config should be removed by Reload")
+  }
+  if _, ok := r.Get("c"); !ok {
+    t.Error("newly added check c should be present after Reload")
+  }
+}
+
+func TestRegistryReloadPreservesStatsForUnchangedCheck(t *testing.T) {
+  r := newTestRegistry()
+  r.Add(tcpCheck("a", "a.example.com", 80))
+  r.RecordResult(CheckResult{Domain: "example.com", Result: true})
+  r.RecordResult(CheckResult{Domain: "example.com", Result: false})
+
+  r.Reload([]Check{tcpCheck("a", "a.example.com", 80)})
+
+  stats := r.StatsSnapshot()["example.com"]
+  if stats.Total != 2 || stats.Up != 1 {
+    t.Errorf("Reload of an unchanged check must not reset accumulated stats, got %+v", stats)
+  }
+}
+
+func TestRegistryReloadRestartsMutatedCheck(t *testing.T) {
+  r := newTestRegistry()
+  r.Add(tcpCheck("a", "a.example.com", 80))
+
+  mutated := tcpCheck("a", "a.example.com", 443)
+  r.Reload([]Check{mutated})
+
+  got, ok := r.Get("a")
+  if !ok {
+    t.Fatal("mutated check a should still be present after Reload")
+  }
+  if got.Port != 443 {
+    t.Errorf("Reload should have applied the mutated port, got %d", got.Port)
+  }
+}
+
+func TestCoordinatorIngestSingleVantageMatchesQuorum(t *testing.T) {
+  c := NewCoordinator(newTestRegistry(), 1)
+
+  c.Ingest(VantageReport{Vantage: "us-east", Results: []CheckResult{
+    {OriginalCheck: Check{Name: "a"}, Domain: "example.com", Result: true},
+  }})
+
+  stats := c.vantageStats["example.com"][aggregatedVantage]
+  if stats.Total != 1 || stats.Up != 1 {
+    t.Errorf("quorum 1 with a single up vote should count as aggregated up, got %+v", stats)
+  }
+}
+
+func TestCoordinatorIngestQuorumRequiresEnoughVantagesUp(t *testing.T) {
+  c := NewCoordinator(newTestRegistry(), 2)
+
+  c.Ingest(VantageReport{Vantage: "us-east", Results: []CheckResult{
+    {OriginalCheck: Check{Name: "a"}, Domain: "example.com", Result: true},
+  }})
+  aggAfterFirst := c.vantageStats["example.com"][aggregatedVantage]
+  if aggAfterFirst.Up != 0 {
+    t.Errorf("a single up vote should not satisfy quorum 2, got %+v", aggAfterFirst)
+  }
+
+  c.Ingest(VantageReport{Vantage: "eu-west", Results: []CheckResult{
+    {OriginalCheck: Check{Name: "a"}, Domain: "example.com", Result: true},
+  }})
+  aggAfterSecond := c.vantageStats["example.com"][aggregatedVantage]
+  if aggAfterSecond.Up != 1 {
+    t.Errorf("two up votes should satisfy quorum 2, got %+v", aggAfterSecond)
+  }
+}
+
+func TestCoordinatorIngestVoteIsLatestPerVantage(t *testing.T) {
+  c := NewCoordinator(newTestRegistry(), 1)
+
+  c.Ingest(VantageReport{Vantage: "us-east", Results: []CheckResult{
+    {OriginalCheck: Check{Name: "a"}, Domain: "example.com", Result: true},
+  }})
+  c.Ingest(VantageReport{Vantage: "us-east", Results: []CheckResult{
+    {OriginalCheck: Check{Name: "a"}, Domain: "example.com", Result: false},
+  }})
+
+  votes := c.votes["a"]
+  if votes["us-east"] {
+    t.Error("a vantage's later report should replace its earlier vote, not accumulate")
+  }
+}
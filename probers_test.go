@@ -0,0 +1,297 @@
+package main
+
+import (
+  "context"
+  "crypto/rand"
+  "crypto/rsa"
+  "crypto/tls"
+  "crypto/x509"
+  "crypto/x509/pkix"
+  "fmt"
+  "math/big"
+  "net"
+  "net/http"
+  "net/http/httptest"
+  "testing"
+  "time"
+)
+
+func TestHTTPProberUpOn2xx(t *testing.T) {
+  srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    w.WriteHeader(http.StatusOK)
+  }))
+  defer srv.Close()
+
+  check := Check{Name: "ok", Type: "http", URL: srv.URL, Method: "GET", Timeout: Duration{time.Second}}
+  prober, err := newHTTPProber(check)
+  if err != nil {
+    t.Fatal(err)
+  }
+  up, stats, err := prober.Probe(context.Background())
+  if err != nil {
+    t.Fatalf("unexpected error: %v", err)
+  }
+  if !up {
+    t.Error("expected a 200 response to count as up")
+  }
+  if stats.StatusCode != http.StatusOK {
+    t.Errorf("expected StatusCode 200, got %d", stats.StatusCode)
+  }
+}
+
+func TestHTTPProberDownOn5xx(t *testing.T) {
+  srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    w.WriteHeader(http.StatusServiceUnavailable)
+  }))
+  defer srv.Close()
+
+  check := Check{Name: "bad", Type: "http", URL: srv.URL, Method: "GET", Timeout: Duration{time.Second}}
+  prober, err := newHTTPProber(check)
+  if err != nil {
+    t.Fatal(err)
+  }
+  up, stats, err := prober.Probe(context.Background())
+  if err != nil {
+    t.Fatalf("unexpected error: %v", err)
+  }
+  if up {
+    t.Error("expected a 503 response to count as down")
+  }
+  if stats.StatusCode != http.StatusServiceUnavailable {
+    t.Errorf("expected StatusCode 503, got %d", stats.StatusCode)
+  }
+}
+
+func TestHTTPProberErrorsOnContextDeadline(t *testing.T) {
+  srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    time.Sleep(200 * time.Millisecond)
+    w.WriteHeader(http.StatusOK)
+  }))
+  defer srv.Close()
+
+  check := Check{Name: "slow", Type: "http", URL: srv.URL, Method: "GET", Timeout: Duration{time.Second}}
+  prober, err := newHTTPProber(check)
+  if err != nil {
+    t.Fatal(err)
+  }
+  ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+  defer cancel()
+  up, _, err := prober.Probe(ctx)
+  if err == nil {
+    t.Fatal("expected an error when the server outlives the context deadline")
+  }
+  if up {
+    t.Error("expected a timed-out probe to count as down")
+  }
+}
+
+func TestTCPProberUpWhenListening(t *testing.T) {
+  ln, err := net.Listen("tcp", "127.0.0.1:0")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer ln.Close()
+  go func() {
+    for {
+      conn, err := ln.Accept()
+      if err != nil {
+        return
+      }
+      conn.Close()
+    }
+  }()
+
+  host, portStr, err := net.SplitHostPort(ln.Addr().String())
+  if err != nil {
+    t.Fatal(err)
+  }
+  var port int
+  fmt.Sscanf(portStr, "%d", &port)
+
+  check := Check{Name: "tcp-up", Type: "tcp", Host: host, Port: port}
+  prober, err := newTCPProber(check)
+  if err != nil {
+    t.Fatal(err)
+  }
+  up, _, err := prober.Probe(context.Background())
+  if err != nil {
+    t.Fatalf("unexpected error: %v", err)
+  }
+  if !up {
+    t.Error("expected a listening port to count as up")
+  }
+}
+
+func TestTCPProberDownWhenNothingListening(t *testing.T) {
+  ln, err := net.Listen("tcp", "127.0.0.1:0")
+  if err != nil {
+    t.Fatal(err)
+  }
+  addr := ln.Addr().String()
+  ln.Close() // nothing is listening on this port anymore
+
+  host, portStr, err := net.SplitHostPort(addr)
+  if err != nil {
+    t.Fatal(err)
+  }
+  var port int
+  fmt.Sscanf(portStr, "%d", &port)
+
+  check := Check{Name: "tcp-down", Type: "tcp", Host: host, Port: port}
+  prober, err := newTCPProber(check)
+  if err != nil {
+    t.Fatal(err)
+  }
+  ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+  defer cancel()
+  up, _, err := prober.Probe(ctx)
+  if err == nil {
+    t.Fatal("expected dialing a closed port to error")
+  }
+  if up {
+    t.Error("expected a closed port to count as down")
+  }
+}
+
+// selfSignedCert builds a throwaway leaf certificate expiring at notAfter,
+// for exercising tlsProber's handshake path without a real CA.
+func selfSignedCert(t *testing.T, notAfter time.Time) tls.Certificate {
+  t.Helper()
+  key, err := rsa.GenerateKey(rand.Reader, 2048)
+  if err != nil {
+    t.Fatal(err)
+  }
+  template := &x509.Certificate{
+    SerialNumber: big.NewInt(1),
+    Subject: pkix.Name{CommonName: "localhost"},
+    NotBefore: time.Now().Add(-time.Hour),
+    NotAfter: notAfter,
+    KeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+    ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+  }
+  der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestTLSProberFailsHandshakeForUntrustedCert(t *testing.T) {
+  cert := selfSignedCert(t, time.Now().Add(365*24*time.Hour))
+  ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer ln.Close()
+  go func() {
+    for {
+      conn, err := ln.Accept()
+      if err != nil {
+        return
+      }
+      conn.Close()
+    }
+  }()
+
+  host, portStr, err := net.SplitHostPort(ln.Addr().String())
+  if err != nil {
+    t.Fatal(err)
+  }
+  var port int
+  fmt.Sscanf(portStr, "%d", &port)
+
+  check := Check{Name: "tls", Type: "tls", Host: host, Port: port}
+  prober, err := newTLSProber(check)
+  if err != nil {
+    t.Fatal(err)
+  }
+  ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+  defer cancel()
+  up, _, err := prober.Probe(ctx)
+  if err == nil {
+    t.Fatal("expected a self-signed, untrusted cert to fail the handshake")
+  }
+  if up {
+    t.Error("a failed handshake must not count as up")
+  }
+}
+
+func TestCertExpiryOKBoundary(t *testing.T) {
+  window := time.Hour
+  cases := []struct {
+    name string
+    expiry time.Time
+    want bool
+  }{
+    {"expires well past the window", time.Now().Add(24 * time.Hour), true},
+    {"expires inside the window", time.Now().Add(30 * time.Minute), false},
+    {"already expired", time.Now().Add(-time.Minute), false},
+  }
+  for _, tc := range cases {
+    t.Run(tc.name, func(t *testing.T) {
+      if got := certExpiryOK(tc.expiry, window); got != tc.want {
+        t.Errorf("certExpiryOK(%v, %v) = %v, want %v", tc.expiry, window, got, tc.want)
+      }
+    })
+  }
+}
+
+func TestDNSProberResolvesLocalhost(t *testing.T) {
+  check := Check{Name: "dns-ok", Type: "dns", Host: "localhost", RecordType: "A", ExpectedAnswer: "127.0.0.1"}
+  prober, err := newDNSProber(check)
+  if err != nil {
+    t.Fatal(err)
+  }
+  up, _, err := prober.Probe(context.Background())
+  if err != nil {
+    t.Fatalf("unexpected error resolving localhost: %v", err)
+  }
+  if !up {
+    t.Error("expected localhost to resolve to 127.0.0.1 and match ExpectedAnswer")
+  }
+}
+
+func TestDNSProberDownOnAnswerMismatch(t *testing.T) {
+  check := Check{Name: "dns-mismatch", Type: "dns", Host: "localhost", RecordType: "A", ExpectedAnswer: "10.0.0.99"}
+  prober, err := newDNSProber(check)
+  if err != nil {
+    t.Fatal(err)
+  }
+  up, _, err := prober.Probe(context.Background())
+  if err == nil {
+    t.Fatal("expected a mismatched ExpectedAnswer to return an error")
+  }
+  if up {
+    t.Error("a mismatched ExpectedAnswer must not count as up")
+  }
+}
+
+func TestExecProberUpOnZeroExit(t *testing.T) {
+  check := Check{Name: "exec-ok", Type: "exec", Script: "true"}
+  prober, err := newExecProber(check)
+  if err != nil {
+    t.Fatal(err)
+  }
+  up, _, err := prober.Probe(context.Background())
+  if err != nil {
+    t.Fatalf("unexpected error: %v", err)
+  }
+  if !up {
+    t.Error("expected exit code 0 to count as up")
+  }
+}
+
+func TestExecProberDownOnNonZeroExit(t *testing.T) {
+  check := Check{Name: "exec-bad", Type: "exec", Script: "false"}
+  prober, err := newExecProber(check)
+  if err != nil {
+    t.Fatal(err)
+  }
+  up, _, err := prober.Probe(context.Background())
+  if err == nil {
+    t.Fatal("expected a non-zero exit to return an error")
+  }
+  if up {
+    t.Error("a non-zero exit must not count as up")
+  }
+}